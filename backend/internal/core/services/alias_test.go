@@ -0,0 +1,35 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestJSONImporter_ResolvesSchemaAliases is an end-to-end regression test for a bug where a
+// header matching a SchemaField alias (rather than its canonical "HB.*" name) validated fine
+// but its value was silently dropped, because rowsFromHeaderMaps looked the column up by its
+// canonical name only.
+func TestJSONImporter_ResolvesSchemaAliases(t *testing.T) {
+	schema := ImportSchema{
+		Fields: []SchemaField{
+			{Column: "HB.name"},
+			{Column: "HB.quantity", Aliases: []string{"Quantity"}, Type: SchemaFieldNumber},
+		},
+	}
+
+	input := `[{"HB.name":"Drill","Quantity":"3"}]`
+
+	rows, err := jsonImporter{}.Read(strings.NewReader(input), schema, ColumnMapping{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if rows[0].Name != "Drill" {
+		t.Fatalf("expected Name %q, got %q", "Drill", rows[0].Name)
+	}
+	if rows[0].Quantity != 3 {
+		t.Fatalf("expected the Quantity alias to resolve to HB.quantity, got Quantity=%d", rows[0].Quantity)
+	}
+}
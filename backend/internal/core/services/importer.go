@@ -0,0 +1,575 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/homebox/backend/internal/core/services/reporting"
+	"github.com/hay-kot/homebox/backend/internal/data/repo"
+	"github.com/xuri/excelize/v2"
+)
+
+// ImportField is a single custom field attached to an ImportRow.
+type ImportField struct {
+	Name  string
+	Value string
+}
+
+// ImportRow is the normalized, format-independent representation of a single row of an
+// import sheet. Every Importer implementation produces these, regardless of whether the
+// underlying upload was CSV, TSV, XLSX or JSON, so the rest of CsvImport never has to care
+// which format the user uploaded.
+type ImportRow struct {
+	ImportRef string
+	Location  []string
+	LabelStr  []string
+	AssetID   repo.AssetID
+	Archived  bool
+
+	Name        string
+	Quantity    int
+	Description string
+	Insured     bool
+	Notes       string
+
+	PurchasePrice float64
+	PurchaseFrom  string
+	PurchaseTime  time.Time
+
+	Manufacturer string
+	ModelNumber  string
+	SerialNumber string
+
+	LifetimeWarranty bool
+	WarrantyExpires  time.Time
+	WarrantyDetails  string
+
+	SoldTo    string
+	SoldPrice float64
+	SoldTime  time.Time
+	SoldNotes string
+
+	Fields []ImportField
+}
+
+// Fingerprint returns a stable hash over every field CsvImport can write for this row,
+// including Name, Quantity, AssetID, Location and LabelStr. It is stored per-item via
+// ItemImportHashesRepository so a later re-import of an unchanged row can be recognized as a
+// no-op instead of writing over it again. Nothing that csvImportRows writes is left out of
+// the hash: the reconciliation logic in csvImportRows treats a fingerprint match as "nothing
+// to do" and continues before reaching the label/location/name sync code below it, so any
+// field left out here would silently stop being synced on re-import.
+func (r ImportRow) Fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%s\x00%t\x00%s\x00%s\x00%.4f\x00%s\x00%s\x00%s\x00%s\x00%s\x00%t\x00%s\x00%s\x00%s\x00%.4f\x00%s\x00%t",
+		r.Name,
+		serializeLocation(r.Location),
+		r.AssetID,
+		strings.Join(r.LabelStr, ","),
+		r.Description,
+		r.Insured,
+		r.Notes,
+		r.PurchaseFrom,
+		r.PurchasePrice,
+		r.PurchaseTime.UTC().Format(time.RFC3339),
+		r.Manufacturer,
+		r.ModelNumber,
+		r.SerialNumber,
+		r.WarrantyDetails,
+		r.LifetimeWarranty,
+		r.WarrantyExpires.UTC().Format(time.RFC3339),
+		r.SoldTo,
+		r.SoldTime.UTC().Format(time.RFC3339),
+		r.SoldPrice,
+		r.SoldNotes,
+		r.Archived,
+	)
+
+	fmt.Fprintf(h, "\x00%d", r.Quantity)
+
+	for _, field := range r.Fields {
+		fmt.Fprintf(h, "\x00%s=%s", field.Name, field.Value)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// mergeRow fills ConflictMerge's "only the empty fields" rule for the text, price and date
+// fields below: wherever existing already has a non-empty value, that value wins over the
+// freshly imported row; fields the existing item has never had a value for take the row's
+// value. Name, Quantity, AssetID, Insured, Archived, LifetimeWarranty, Location, LabelStr and
+// Fields are not merged and always take the row's value.
+func mergeRow(existing repo.ItemOut, row ImportRow) ImportRow {
+	merged := row
+
+	if existing.Description != "" {
+		merged.Description = existing.Description
+	}
+	if existing.Notes != "" {
+		merged.Notes = existing.Notes
+	}
+	if existing.PurchaseFrom != "" {
+		merged.PurchaseFrom = existing.PurchaseFrom
+	}
+	if existing.PurchasePrice != 0 {
+		merged.PurchasePrice = existing.PurchasePrice
+	}
+	if !existing.PurchaseTime.IsZero() {
+		merged.PurchaseTime = existing.PurchaseTime
+	}
+	if existing.Manufacturer != "" {
+		merged.Manufacturer = existing.Manufacturer
+	}
+	if existing.ModelNumber != "" {
+		merged.ModelNumber = existing.ModelNumber
+	}
+	if existing.SerialNumber != "" {
+		merged.SerialNumber = existing.SerialNumber
+	}
+	if existing.WarrantyDetails != "" {
+		merged.WarrantyDetails = existing.WarrantyDetails
+	}
+	if !existing.WarrantyExpires.IsZero() {
+		merged.WarrantyExpires = existing.WarrantyExpires
+	}
+	if existing.SoldTo != "" {
+		merged.SoldTo = existing.SoldTo
+	}
+	if existing.SoldPrice != 0 {
+		merged.SoldPrice = existing.SoldPrice
+	}
+	if !existing.SoldTime.IsZero() {
+		merged.SoldTime = existing.SoldTime
+	}
+	if existing.SoldNotes != "" {
+		merged.SoldNotes = existing.SoldNotes
+	}
+
+	return merged
+}
+
+// Importer converts a raw upload into a slice of ImportRow. Implementations are registered
+// by file extension and MIME type in the package-level importer registry and selected by
+// the HTTP layer (or sniffed via DetectFormat when neither is known).
+type Importer interface {
+	// DetectFormat reports whether data looks like this importer's format. It must only
+	// Peek at data, never Read from it, since the same *bufio.Reader is offered to every
+	// registered Importer in turn until one claims it.
+	DetectFormat(data *bufio.Reader) bool
+
+	// Read parses data into rows, validating headers against schema (after mapping has
+	// renamed any of the user's own header names to their canonical schema column) and
+	// returning the error from ColumnValidation.Err if they don't match.
+	// Only called once a format has been selected, either because the caller supplied an
+	// extension/MIME hint or DetectFormat matched.
+	Read(data io.Reader, schema ImportSchema, mapping ColumnMapping) ([]ImportRow, error)
+}
+
+var importerRegistry = map[string]Importer{}
+
+// RegisterImporter associates an Importer with a file extension (e.g. ".csv") and/or a MIME
+// type (e.g. "text/csv"). Either key may be left blank if it doesn't apply.
+func RegisterImporter(ext, mime string, imp Importer) {
+	if ext != "" {
+		importerRegistry[strings.ToLower(ext)] = imp
+	}
+	if mime != "" {
+		importerRegistry[strings.ToLower(mime)] = imp
+	}
+}
+
+func init() {
+	sheet := sheetImporter{}
+	RegisterImporter(".csv", "text/csv", sheet)
+	RegisterImporter(".tsv", "text/tab-separated-values", sheet)
+	RegisterImporter(".xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", xlsxImporter{})
+	RegisterImporter(".json", "application/json", jsonImporter{})
+}
+
+// ResolveImporter looks up an Importer by extension or MIME type first, falling back to
+// DetectFormat sniffing against the registered importers when neither hint is recognized.
+func ResolveImporter(ext, mime string, data *bufio.Reader) (Importer, error) {
+	if imp, ok := importerRegistry[strings.ToLower(ext)]; ok {
+		return imp, nil
+	}
+
+	if imp, ok := importerRegistry[strings.ToLower(mime)]; ok {
+		return imp, nil
+	}
+
+	for _, imp := range importerRegistry {
+		if imp.DetectFormat(data) {
+			return imp, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not detect import file format")
+}
+
+var expectedHeaders = []string{"HB.import_ref", "HB.location", "HB.labels", "HB.asset_id", "HB.archived", "HB.name", "HB.quantity", "HB.description", "HB.insured", "HB.notes", "HB.purchase_price", "HB.purchase_from", "HB.purchase_time", "HB.manufacturer", "HB.model_number", "HB.serial_number", "HB.lifetime_warranty", "HB.warranty_expires", "HB.warranty_details", "HB.sold_to", "HB.sold_price", "HB.sold_time", "HB.sold_notes"}
+
+// sheetImporter is the original reporting.IOSheet based implementation and handles both CSV
+// and TSV, since reporting.IOSheet already sniffs the delimiter between the two.
+//
+// Unlike xlsxImporter and jsonImporter, sheetImporter cannot auto-map unrecognized
+// "HB."-prefixed columns to custom fields: reporting.IOSheet parses a row straight into its
+// fixed set of named fields and never hands back the raw header/cell pairs needed to do so.
+// Such a column still passes schema.Validate (it lands in ColumnValidation.CustomFields), but
+// sheetImporter ignores that list. mapping and schema aliases are likewise used to validate
+// the headers only: reporting.IOSheet has already parsed the sheet without them.
+type sheetImporter struct{}
+
+func (sheetImporter) DetectFormat(data *bufio.Reader) bool {
+	peek, _ := data.Peek(512)
+	return bytes.Contains(peek, []byte("HB."))
+}
+
+func (sheetImporter) Read(data io.Reader, schema ImportSchema, mapping ColumnMapping) ([]ImportRow, error) {
+	sheet := reporting.IOSheet{}
+	if err := sheet.Read(data); err != nil {
+		return nil, err
+	}
+
+	headers := mapping.Apply(sheet.GetHeaders())
+	if validation := schema.Validate(headers); !validation.OK() {
+		return nil, validation.Err()
+	}
+
+	rows := make([]ImportRow, len(sheet.Rows))
+	for i, row := range sheet.Rows {
+		fields := make([]ImportField, len(row.Fields))
+		for j, f := range row.Fields {
+			fields[j] = ImportField{Name: f.Name, Value: f.Value}
+		}
+
+		rows[i] = ImportRow{
+			ImportRef:        row.ImportRef,
+			Location:         row.Location,
+			LabelStr:         row.LabelStr,
+			AssetID:          row.AssetID,
+			Archived:         row.Archived,
+			Name:             row.Name,
+			Quantity:         row.Quantity,
+			Description:      row.Description,
+			Insured:          row.Insured,
+			Notes:            row.Notes,
+			PurchasePrice:    row.PurchasePrice,
+			PurchaseFrom:     row.PurchaseFrom,
+			PurchaseTime:     row.PurchaseTime,
+			Manufacturer:     row.Manufacturer,
+			ModelNumber:      row.ModelNumber,
+			SerialNumber:     row.SerialNumber,
+			LifetimeWarranty: row.LifetimeWarranty,
+			WarrantyExpires:  row.WarrantyExpires,
+			WarrantyDetails:  row.WarrantyDetails,
+			SoldTo:           row.SoldTo,
+			SoldPrice:        row.SoldPrice,
+			SoldTime:         row.SoldTime,
+			SoldNotes:        row.SoldNotes,
+			Fields:           fields,
+		}
+	}
+
+	return rows, nil
+}
+
+// xlsxImporter reads the first sheet of an XLSX workbook, treating row 1 as headers in the
+// same "HB.*" format the CSV/TSV importer expects. This lets users upload the spreadsheet
+// they exported from Homebox without re-saving it as CSV first.
+type xlsxImporter struct{}
+
+func (xlsxImporter) DetectFormat(data *bufio.Reader) bool {
+	peek, err := data.Peek(2)
+	return err == nil && peek[0] == 'P' && peek[1] == 'K'
+}
+
+func (xlsxImporter) Read(data io.Reader, schema ImportSchema, mapping ColumnMapping) ([]ImportRow, error) {
+	f, err := excelize.OpenReader(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xlsx file: %w", err)
+	}
+	defer f.Close()
+
+	sheetName := f.GetSheetName(0)
+
+	grid, err := f.GetRows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read xlsx sheet %q: %w", sheetName, err)
+	}
+
+	if len(grid) == 0 {
+		return nil, fmt.Errorf("xlsx sheet %q is empty", sheetName)
+	}
+
+	rawHeaders := grid[0]
+	headers := resolveAliases(mapping.Apply(rawHeaders), schema)
+
+	validation := schema.Validate(headers)
+	if !validation.OK() {
+		return nil, validation.Err()
+	}
+
+	rowsByHeader := make([]map[string]string, 0, len(grid)-1)
+	for _, line := range grid[1:] {
+		byHeader := make(map[string]string, len(headers))
+		for i, header := range headers {
+			if i < len(line) {
+				byHeader[header] = line[i]
+			}
+		}
+		rowsByHeader = append(rowsByHeader, byHeader)
+	}
+
+	return rowsFromHeaderMaps(rowsByHeader, validation.CustomFields, schema)
+}
+
+// jsonImporter reads an array of flat JSON objects keyed by the same "HB.*" column names
+// used by the CSV/TSV format. Every value is turned into its fmt "%v" string form and then
+// parsed the same way as an XLSX cell.
+type jsonImporter struct{}
+
+func (jsonImporter) DetectFormat(data *bufio.Reader) bool {
+	// Peek a bounded window rather than Peek(1)-then-Discard-ing leading whitespace: data is
+	// the same *bufio.Reader offered to every registered Importer in turn, and Discard would
+	// permanently consume bytes even for an upload this importer doesn't end up claiming.
+	peek, _ := data.Peek(512)
+	for _, b := range peek {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func (jsonImporter) Read(data io.Reader, schema ImportSchema, mapping ColumnMapping) ([]ImportRow, error) {
+	var raw []map[string]any
+
+	if err := json.NewDecoder(data).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON import: %w", err)
+	}
+
+	rowsByHeader := make([]map[string]string, len(raw))
+	var headers []string
+	seenHeader := make(map[string]bool)
+	for i, obj := range raw {
+		byHeader := make(map[string]string, len(obj))
+		for k, v := range obj {
+			byHeader[k] = fmt.Sprintf("%v", v)
+			if !seenHeader[k] {
+				seenHeader[k] = true
+				headers = append(headers, k)
+			}
+		}
+		rowsByHeader[i] = byHeader
+	}
+
+	headers = resolveAliases(mapping.Apply(headers), schema)
+
+	aliases := aliasIndex(schema)
+	for i, byHeader := range rowsByHeader {
+		rowsByHeader[i] = rekeyHeaders(byHeader, mapping, aliases)
+	}
+
+	validation := schema.Validate(headers)
+	if !validation.OK() {
+		return nil, validation.Err()
+	}
+
+	return rowsFromHeaderMaps(rowsByHeader, validation.CustomFields, schema)
+}
+
+// rekeyHeaders returns a copy of byHeader with any key the mapping covers, or that names a
+// SchemaField alias, renamed to its canonical column name, mirroring what resolveAliases does
+// for a plain header slice. mapping takes priority over aliases, matching the precedence
+// ColumnMapping.Apply already has over schema.Validate's own alias handling.
+func rekeyHeaders(byHeader map[string]string, mapping ColumnMapping, aliases map[string]string) map[string]string {
+	if len(mapping) == 0 && len(aliases) == 0 {
+		return byHeader
+	}
+
+	rekeyed := make(map[string]string, len(byHeader))
+	for k, v := range byHeader {
+		if column, ok := mapping[k]; ok {
+			rekeyed[column] = v
+		} else if column, ok := aliases[k]; ok {
+			rekeyed[column] = v
+		} else {
+			rekeyed[k] = v
+		}
+	}
+	return rekeyed
+}
+
+// aliasIndex maps every SchemaField alias to its canonical column name.
+func aliasIndex(schema ImportSchema) map[string]string {
+	index := make(map[string]string)
+	for _, field := range schema.Fields {
+		for _, alias := range field.Aliases {
+			index[alias] = field.Column
+		}
+	}
+	return index
+}
+
+// resolveAliases renames any header that names a SchemaField alias (e.g. "Quantity") to its
+// canonical column name (e.g. "HB.quantity"), so the literal byHeader["HB.quantity"] lookups
+// in rowsFromHeaderMaps find a row's value regardless of which name the header arrived under.
+func resolveAliases(headers []string, schema ImportSchema) []string {
+	aliases := aliasIndex(schema)
+
+	resolved := make([]string, len(headers))
+	for i, header := range headers {
+		if column, ok := aliases[header]; ok {
+			resolved[i] = column
+		} else {
+			resolved[i] = header
+		}
+	}
+	return resolved
+}
+
+// rowsFromHeaderMaps adapts the column-name-keyed rows produced by the XLSX and JSON
+// importers into ImportRow, sharing the same field layout the CSV/TSV importer produces.
+// customFields lists the "HB."-prefixed headers the schema didn't recognize; their values
+// are carried onto ImportRow.Fields instead of being dropped. Unlike the CSV/TSV importer
+// (which relies on reporting.IOSheet's own cell parsing), XLSX and JSON hand back every value
+// as a string, so each built-in column is parsed by a fixed rule: numbers and dates through
+// parseNumber and parseDate, bools as the literal "true". schema's declared field Types only
+// appear in the error for a value that fails to parse.
+func rowsFromHeaderMaps(rowsByHeader []map[string]string, customFields []string, schema ImportSchema) ([]ImportRow, error) {
+	rows := make([]ImportRow, len(rowsByHeader))
+	for i, byHeader := range rowsByHeader {
+		fields := make([]ImportField, 0, len(customFields))
+		for _, column := range customFields {
+			if value, ok := byHeader[column]; ok {
+				fields = append(fields, ImportField{Name: strings.TrimPrefix(column, "HB."), Value: value})
+			}
+		}
+
+		quantity, err := parseNumber(schema, "HB.quantity", byHeader["HB.quantity"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		assetID, err := parseNumber(schema, "HB.asset_id", byHeader["HB.asset_id"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		purchasePrice, err := parseNumber(schema, "HB.purchase_price", byHeader["HB.purchase_price"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		purchaseTime, err := parseDate(schema, "HB.purchase_time", byHeader["HB.purchase_time"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		warrantyExpires, err := parseDate(schema, "HB.warranty_expires", byHeader["HB.warranty_expires"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		soldPrice, err := parseNumber(schema, "HB.sold_price", byHeader["HB.sold_price"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		soldTime, err := parseDate(schema, "HB.sold_time", byHeader["HB.sold_time"])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		rows[i] = ImportRow{
+			ImportRef:        byHeader["HB.import_ref"],
+			Location:         splitNonEmpty(byHeader["HB.location"], "/"),
+			LabelStr:         splitNonEmpty(byHeader["HB.labels"], ";"),
+			AssetID:          repo.AssetID(assetID),
+			Name:             byHeader["HB.name"],
+			Quantity:         int(quantity),
+			Description:      byHeader["HB.description"],
+			Notes:            byHeader["HB.notes"],
+			PurchasePrice:    purchasePrice,
+			PurchaseFrom:     byHeader["HB.purchase_from"],
+			PurchaseTime:     purchaseTime,
+			Manufacturer:     byHeader["HB.manufacturer"],
+			ModelNumber:      byHeader["HB.model_number"],
+			SerialNumber:     byHeader["HB.serial_number"],
+			WarrantyDetails:  byHeader["HB.warranty_details"],
+			WarrantyExpires:  warrantyExpires,
+			SoldTo:           byHeader["HB.sold_to"],
+			SoldPrice:        soldPrice,
+			SoldTime:         soldTime,
+			SoldNotes:        byHeader["HB.sold_notes"],
+			Archived:         byHeader["HB.archived"] == "true",
+			Insured:          byHeader["HB.insured"] == "true",
+			LifetimeWarranty: byHeader["HB.lifetime_warranty"] == "true",
+			Fields:           fields,
+		}
+	}
+
+	return rows, nil
+}
+
+// fieldType returns column's declared SchemaFieldType, or SchemaFieldText if schema doesn't
+// mention it (e.g. a custom profile that left a built-in column's type unset).
+func fieldType(schema ImportSchema, column string) SchemaFieldType {
+	for _, field := range schema.Fields {
+		if field.Column == column {
+			if field.Type == "" {
+				break
+			}
+			return field.Type
+		}
+	}
+	return SchemaFieldText
+}
+
+// parseNumber parses a numeric column's raw string value, returning 0 for an empty value.
+func parseNumber(schema ImportSchema, column, value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: expected a number (schema type %q), got %q", column, fieldType(schema, column), value)
+	}
+	return f, nil
+}
+
+// parseDate parses a date column's raw string value, accepting RFC3339 or a bare
+// YYYY-MM-DD, and returning the zero time for an empty value.
+func parseDate(schema ImportSchema, column, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: expected a date (schema type %q), got %q", column, fieldType(schema, column), value)
+}
+
+func splitNonEmpty(s, sep string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, sep)
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImportRow_Fingerprint(t *testing.T) {
+	base := ImportRow{
+		Name:     "Drill",
+		Location: []string{"Garage", "Shelf 1"},
+		LabelStr: []string{"Tools"},
+		Quantity: 2,
+	}
+
+	t.Run("identical rows hash the same", func(t *testing.T) {
+		other := base
+		if base.Fingerprint() != other.Fingerprint() {
+			t.Fatal("expected identical rows to produce the same fingerprint")
+		}
+	})
+
+	cases := []struct {
+		name   string
+		modify func(row ImportRow) ImportRow
+	}{
+		{"name changes", func(row ImportRow) ImportRow { row.Name = "Impact Driver"; return row }},
+		{"quantity changes", func(row ImportRow) ImportRow { row.Quantity = 3; return row }},
+		{"location changes", func(row ImportRow) ImportRow { row.Location = []string{"Garage", "Shelf 2"}; return row }},
+		{"labels change", func(row ImportRow) ImportRow { row.LabelStr = []string{"Tools", "Power"}; return row }},
+		{"description changes", func(row ImportRow) ImportRow { row.Description = "18V"; return row }},
+		{"purchase time changes", func(row ImportRow) ImportRow {
+			row.PurchaseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			return row
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			changed := tc.modify(base)
+			if base.Fingerprint() == changed.Fingerprint() {
+				t.Fatalf("expected %s to change the fingerprint", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestResolveImporter(t *testing.T) {
+	t.Run("resolves by extension", func(t *testing.T) {
+		imp, err := ResolveImporter(".csv", "", bufio.NewReader(strings.NewReader("HB.name\nDrill\n")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := imp.(sheetImporter); !ok {
+			t.Fatalf("expected sheetImporter, got %T", imp)
+		}
+	})
+
+	t.Run("resolves by mime type", func(t *testing.T) {
+		imp, err := ResolveImporter("", "application/json", bufio.NewReader(strings.NewReader("[]")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := imp.(jsonImporter); !ok {
+			t.Fatalf("expected jsonImporter, got %T", imp)
+		}
+	})
+
+	t.Run("falls back to sniffing a JSON array with no extension or mime hint", func(t *testing.T) {
+		imp, err := ResolveImporter("", "", bufio.NewReader(strings.NewReader("  [{\"name\":\"Drill\"}]")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := imp.(jsonImporter); !ok {
+			t.Fatalf("expected jsonImporter, got %T", imp)
+		}
+	})
+
+	t.Run("unrecognized format errors", func(t *testing.T) {
+		_, err := ResolveImporter("", "", bufio.NewReader(strings.NewReader("not a known format at all")))
+		if err == nil {
+			t.Fatal("expected an error for an unrecognized format")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestImportSchema_Validate(t *testing.T) {
+	schema := ImportSchema{
+		Fields: []SchemaField{
+			{Column: "HB.name", Required: true},
+			{Column: "HB.quantity", Aliases: []string{"Quantity"}},
+		},
+	}
+
+	t.Run("accepts known columns and aliases", func(t *testing.T) {
+		result := schema.Validate([]string{"HB.name", "Quantity"})
+		if !result.OK() {
+			t.Fatalf("expected headers to validate, got %+v", result)
+		}
+	})
+
+	t.Run("flags missing required columns", func(t *testing.T) {
+		result := schema.Validate([]string{"Quantity"})
+		if result.OK() {
+			t.Fatal("expected missing HB.name to fail validation")
+		}
+		if len(result.MissingRequired) != 1 || result.MissingRequired[0] != "HB.name" {
+			t.Fatalf("expected HB.name to be reported missing, got %v", result.MissingRequired)
+		}
+	})
+
+	t.Run("routes unknown HB columns to CustomFields, not Unrecognized", func(t *testing.T) {
+		result := schema.Validate([]string{"HB.name", "HB.custom_field"})
+		if !result.OK() {
+			t.Fatalf("expected a custom field to still validate, got %+v", result)
+		}
+		if len(result.CustomFields) != 1 || result.CustomFields[0] != "HB.custom_field" {
+			t.Fatalf("expected HB.custom_field to be a custom field, got %v", result.CustomFields)
+		}
+	})
+
+	t.Run("rejects a truly unrecognized column", func(t *testing.T) {
+		result := schema.Validate([]string{"HB.name", "Not A Column"})
+		if result.OK() {
+			t.Fatal("expected an unmapped, non-HB column to fail validation")
+		}
+		if len(result.Unrecognized) != 1 || result.Unrecognized[0] != "Not A Column" {
+			t.Fatalf("expected %q to be unrecognized, got %v", "Not A Column", result.Unrecognized)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -89,61 +90,218 @@ func serializeLocation[T ~[]string](location T) string {
 	return strings.Join(location, "/")
 }
 
-// Ooi J Sen
-// Function to validate headers
-func validateHeaders(expected, actual []string) bool {
-	actualHeaderCount := make(map[string]int)
+// OnError controls how CsvImport reacts to a row-level error.
+type OnError string
+
+const (
+	// OnErrorAbort rolls back the whole import as soon as a single row fails.
+	OnErrorAbort OnError = "abort"
+	// OnErrorSkip leaves the failing row out of the import and continues with the rest.
+	OnErrorSkip OnError = "skip"
+	// OnErrorCollect behaves like OnErrorSkip but is intended for dry runs where the
+	// caller wants a full picture of every problem in the sheet, not just the first one.
+	OnErrorCollect OnError = "collect"
+)
 
-	// Count occurrences of headers in the actual slice
-	for _, header := range actual {
-		actualHeaderCount[header]++
-	}
+// ConflictMode controls how csvImportRows reconciles a row whose ImportRef already exists
+// against an item whose stored ImportHash no longer matches the row (i.e. the row changed
+// since the last import).
+type ConflictMode string
+
+const (
+	// ConflictSkip leaves the existing item untouched. This is the literal meaning of "if
+	// the item has a ImportRef and it exists it is skipped" from CsvImport's historical
+	// doc comment, and is the default.
+	ConflictSkip ConflictMode = "skip"
+	// ConflictOverwrite replaces every importable field on the existing item with the
+	// row's values.
+	ConflictOverwrite ConflictMode = "overwrite"
+	// ConflictMerge only fills in the text, price and date fields that are currently empty
+	// on the existing item (see mergeRow). The name, quantity, asset ID, bool flags,
+	// location, labels and custom fields are still overwritten with the row's values.
+	ConflictMerge ConflictMode = "merge"
+	// ConflictCreateRevision records the item's current scalar field values to
+	// item_revisions before overwriting it. Its location, labels, asset ID and custom fields
+	// are not recorded.
+	ConflictCreateRevision ConflictMode = "create_revision"
+)
 
-	// Check if all actual headers are within the expected headers
-	for header, count := range actualHeaderCount {
-		if count > 0 && !contains(expected, header) {
-			return false
-		}
-	}
+// RowStatus is the outcome of importing a single row of a CsvImport.
+type RowStatus string
+
+const (
+	RowStatusCreated RowStatus = "created"
+	RowStatusUpdated RowStatus = "updated"
+	RowStatusSkipped RowStatus = "skipped"
+	RowStatusError   RowStatus = "error"
+)
 
-	return true
+// ImportReport summarizes the outcome of a CsvImport run.
+type ImportReport struct {
+	Total   int          `json:"total"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Skipped int          `json:"skipped"`
+	Errors  []FieldError `json:"errors"`
 }
 
-// Function to check if a string slice contains a specific string
-func contains(slice []string, str string) bool {
-	for _, s := range slice {
-		if s == str {
-			return true
-		}
+// ImportOptions configures a CsvImport run.
+type ImportOptions struct {
+	// DryRun validates and reports on the sheet without writing anything to the database.
+	DryRun bool
+	// OnError controls whether a row-level error aborts the whole import or is recorded
+	// and skipped. Defaults to OnErrorAbort when left blank.
+	OnError OnError
+	// Schema describes the columns the sheet is expected to have. When left zero-valued,
+	// the group's stored import profile is used, or DefaultImportSchema (the historical
+	// "HB.*" columns) if the group has none.
+	Schema ImportSchema
+	// Mapping lets the caller pair their own spreadsheet headers with Schema's canonical
+	// column names, so the user doesn't have to rename their sheet to match Homebox. The
+	// XLSX and JSON importers also use it to read each value; the CSV/TSV importer uses it
+	// to validate the headers only.
+	Mapping ColumnMapping
+	// Conflict controls how a row whose ImportRef already exists, but whose fingerprint
+	// has changed, is reconciled against the existing item. Defaults to ConflictSkip when
+	// left blank.
+	Conflict ConflictMode
+}
+
+// ImportProgress is emitted once per processed row when a progress channel is supplied to
+// CsvImport. This is a service-layer hook only: CsvImport doesn't know or care what the
+// caller does with it, but nothing in this package relays it anywhere (e.g. over SSE) yet -
+// that's a separate, not-yet-written HTTP handler.
+type ImportProgress struct {
+	Row    int
+	Status RowStatus
+}
+
+// groupImportSchema returns GID's configured import profile, parsed with LoadImportSchema,
+// falling back to DefaultImportSchema when the group hasn't uploaded one of its own.
+func (svc *ItemService) groupImportSchema(ctx context.Context, GID uuid.UUID) (ImportSchema, error) {
+	data, format, err := svc.repo.GroupImportProfiles.Get(ctx, GID)
+	if err != nil {
+		return ImportSchema{}, fmt.Errorf("failed to load group %q's import schema profile: %w", GID, err)
+	}
+
+	if len(data) == 0 {
+		return DefaultImportSchema(), nil
 	}
-	return false
+
+	return LoadImportSchema(data, format)
 }
 
-// CsvImport imports items from a CSV file. using the standard defined format.
+// CsvImport imports items from a spreadsheet. Despite the name it is no longer limited to
+// CSV: ext and mime are hints (file extension and upload Content-Type, either may be blank)
+// used to pick a registered Importer, falling back to content sniffing via
+// Importer.DetectFormat when neither hint is recognized. See ResolveImporter and the
+// importers registered in importer.go for the supported formats.
 //
 // CsvImport applies the following rules/operations
 //
 //  1. If the item does not exist, it is created.
 //  2. If the item has a ImportRef and it exists it is skipped
 //  3. Locations and Labels are created if they do not exist.
-func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Reader) (int, error) {
-	sheet := reporting.IOSheet{}
+//
+// The selected Importer parses every row into memory before any row is processed; rows are
+// not streamed. The outcome of each row is recorded on the returned ImportReport instead of
+// aborting partway through with items already written. Rule 2 above holds for
+// opts.Conflict == ConflictSkip and for rows whose fingerprint is unchanged; the other
+// ConflictModes update the existing item. When opts.DryRun is false, the import runs inside a single
+// transaction: with opts.OnError == OnErrorAbort the first row error rolls the whole
+// transaction back, otherwise the row is recorded as an error and the transaction continues.
+// progress may be nil; when non-nil it is sent an ImportProgress per row and closed before
+// CsvImport returns.
+func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Reader, ext, mime string, opts ImportOptions, progress chan<- ImportProgress) (ImportReport, error) {
+	if progress != nil {
+		defer close(progress)
+	}
+
+	if opts.OnError == "" {
+		opts.OnError = OnErrorAbort
+	}
 
-	err := sheet.Read(data)
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+
+	if len(opts.Schema.Fields) == 0 {
+		schema, err := svc.groupImportSchema(ctx, GID)
+		if err != nil {
+			return ImportReport{}, err
+		}
+		opts.Schema = schema
+	}
+
+	report := ImportReport{}
+
+	buffered := bufio.NewReader(data)
+
+	imp, err := ResolveImporter(ext, mime, buffered)
 	if err != nil {
-		return 0, err
+		return report, err
 	}
-	
-	// Ooi J Sen
-	// Access excel sheet headers
-	headers := sheet.GetHeaders()
-
-	// Validate column headers
-	expectedHeaders := []string{"HB.import_ref", "HB.location", "HB.labels", "HB.asset_id", "HB.archived", "HB.name", "HB.quantity", "HB.description", "HB.insured", "HB.notes", "HB.purchase_price", "HB.purchase_from", "HB.purchase_time", "HB.manufacturer", "HB.model_number", "HB.serial_number", "HB.lifetime_warranty", "HB.warranty_expires", "HB.warranty_details", "HB.sold_to", "HB.sold_price", "HB.sold_time", "HB.sold_notes",}
-	if !validateHeaders(expectedHeaders, headers) {
-		return 0, fmt.Errorf("CSV columns do not match the expected format")
+
+	rows, err := imp.Read(buffered, opts.Schema, opts.Mapping)
+	if err != nil {
+		return report, err
+	}
+
+	if opts.DryRun {
+		return svc.csvImportDryRun(rows, opts, progress)
+	}
+
+	txErr := svc.repo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		report, err = svc.csvImportRows(ctx, GID, rows, opts, progress)
+		return err
+	})
+	if txErr != nil {
+		return report, txErr
 	}
 
+	return report, nil
+}
+
+// csvImportDryRun validates every row without touching the database, so the caller can show
+// the user every problem in the sheet before committing to an import. Existing items are not
+// looked up, so every valid row is reported as created.
+func (svc *ItemService) csvImportDryRun(rows []ImportRow, opts ImportOptions, progress chan<- ImportProgress) (ImportReport, error) {
+	report := ImportReport{Total: len(rows)}
+
+	for i := range rows {
+		row := rows[i]
+
+		status := RowStatusCreated
+		if fieldErrs := opts.Schema.ValidateRow(i, &row); len(fieldErrs) > 0 {
+			status = RowStatusError
+			report.Errors = append(report.Errors, fieldErrs...)
+
+			if opts.OnError == OnErrorAbort {
+				if progress != nil {
+					progress <- ImportProgress{Row: i, Status: status}
+				}
+				return report, fmt.Errorf("row %d: %s", fieldErrs[0].Row, fieldErrs[0].Message)
+			}
+
+			report.Skipped++
+		} else {
+			report.Created++
+		}
+
+		if progress != nil {
+			progress <- ImportProgress{Row: i, Status: status}
+		}
+	}
+
+	return report, nil
+}
+
+// csvImportRows performs the writing half of CsvImport. It is only ever called with a
+// non-dry-run ImportOptions, from within the transaction started by CsvImport.
+func (svc *ItemService) csvImportRows(ctx context.Context, GID uuid.UUID, rows []ImportRow, opts ImportOptions, progress chan<- ImportProgress) (ImportReport, error) {
+	report := ImportReport{Total: len(rows)}
+
 	// ========================================
 	// Labels
 
@@ -151,7 +309,7 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 	{
 		labels, err := svc.repo.Labels.GetAll(ctx, GID)
 		if err != nil {
-			return 0, err
+			return report, err
 		}
 
 		for _, label := range labels {
@@ -166,7 +324,7 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 	{
 		locations, err := svc.repo.Locations.Tree(ctx, GID, repo.TreeQuery{WithItems: false})
 		if err != nil {
-			return 0, err
+			return report, err
 		}
 
 		// Traverse the tree and build a map of location full paths to IDs
@@ -193,55 +351,86 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 	// Asset ID Pre-Check
 	highestAID := repo.AssetID(-1)
 	if svc.autoIncrementAssetID {
+		var err error
 		highestAID, err = svc.repo.Items.GetHighestAssetID(ctx, GID)
 		if err != nil {
-			return 0, err
+			return report, err
 		}
 	}
 
-	finished := 0
-
-	var errorMessage string
-
-	for i := range sheet.Rows {
-		row := sheet.Rows[i]
-		
-		var hasNegativeValues bool
+	for i := range rows {
+		row := rows[i]
 
 		createRequired := true
 
 		// ========================================
 		// Preflight check for existing item
+		var existingItem repo.ItemOut
 		if row.ImportRef != "" {
 			exists, err := svc.repo.Items.CheckRef(ctx, GID, row.ImportRef)
 			if err != nil {
-				return 0, fmt.Errorf("error checking for existing item with ref %q: %w", row.ImportRef, err)
+				return report, fmt.Errorf("error checking for existing item with ref %q: %w", row.ImportRef, err)
 			}
 
 			if exists {
 				createRequired = false
+
+				existingItem, err = svc.repo.Items.GetByRef(ctx, GID, row.ImportRef)
+				if err != nil {
+					return report, err
+				}
 			}
 		}
-		
-		// Ooi J Sen
-		// Check integer fields for negative values
-		if row.Quantity < 0 {
-			errorMessage += fmt.Sprintf("Negative quantity at row %d\n", i+1)
-			hasNegativeValues = true
-		}
-		if row.PurchasePrice < 0 {
-			errorMessage += fmt.Sprintf("Negative purchase price at row %d\n", i+1)
-			hasNegativeValues = true
-		}
-		if row.SoldPrice < 0 {
-			errorMessage += fmt.Sprintf("Negative sold price at row %d\n", i+1)
-			hasNegativeValues = true
-		}
 
-		if (hasNegativeValues) {
+		if fieldErrs := opts.Schema.ValidateRow(i, &row); len(fieldErrs) > 0 {
+			report.Errors = append(report.Errors, fieldErrs...)
+
+			if opts.OnError == OnErrorAbort {
+				return report, fmt.Errorf("row %d: %s", fieldErrs[0].Row, fieldErrs[0].Message)
+			}
+
+			report.Skipped++
+			if progress != nil {
+				progress <- ImportProgress{Row: i, Status: RowStatusError}
+			}
 			continue
 		}
 
+		// ========================================
+		// Reconcile against an existing item: idempotent re-imports of an unchanged row
+		// are always a no-op, and a changed row is reconciled per opts.Conflict. rowHash is
+		// taken before any merge, so it is the incoming row's hash that gets stored.
+		rowHash := row.Fingerprint()
+		if !createRequired {
+			existingHash, _, err := svc.repo.ItemImportHashes.Get(ctx, existingItem.ID)
+			if err != nil {
+				return report, fmt.Errorf("failed to load import hash for item %q: %w", existingItem.ID, err)
+			}
+
+			if existingHash == rowHash {
+				report.Skipped++
+				if progress != nil {
+					progress <- ImportProgress{Row: i, Status: RowStatusSkipped}
+				}
+				continue
+			}
+
+			switch opts.Conflict {
+			case ConflictSkip:
+				report.Skipped++
+				if progress != nil {
+					progress <- ImportProgress{Row: i, Status: RowStatusSkipped}
+				}
+				continue
+			case ConflictCreateRevision:
+				if err := svc.repo.ItemRevisions.Create(ctx, GID, existingItem, existingHash); err != nil {
+					return report, fmt.Errorf("failed to record revision for item %q: %w", existingItem.ID, err)
+				}
+			case ConflictMerge:
+				row = mergeRow(existingItem, row)
+			}
+		}
+
 		// ========================================
 		// Pre-Create Labels as necessary
 		labelIds := make([]uuid.UUID, len(row.LabelStr))
@@ -253,7 +442,7 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 			if !ok {
 				newLabel, err := svc.repo.Labels.Create(ctx, GID, repo.LabelCreate{Name: label})
 				if err != nil {
-					return 0, err
+					return report, err
 				}
 				id = newLabel.ID
 			}
@@ -288,7 +477,7 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 						Name:     pathElement,
 					})
 					if err != nil {
-						return 0, err
+						return report, err
 					}
 					locationID = newLocation.ID
 				}
@@ -298,7 +487,7 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 
 			locationID, ok = locationMap[path]
 			if !ok {
-				return 0, errors.New("failed to create location")
+				return report, errors.New("failed to create location")
 			}
 		}
 
@@ -313,6 +502,7 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 		// ========================================
 		// Create Item
 		var item repo.ItemOut
+		var err error
 		switch {
 		case createRequired:
 			newItem := repo.ItemCreate{
@@ -326,13 +516,10 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 
 			item, err = svc.repo.Items.Create(ctx, GID, newItem)
 			if err != nil {
-				return 0, err
+				return report, err
 			}
 		default:
-			item, err = svc.repo.Items.GetByRef(ctx, GID, row.ImportRef)
-			if err != nil {
-				return 0, err
-			}
+			item = existingItem
 		}
 
 		if item.ID == uuid.Nil {
@@ -383,22 +570,29 @@ func (svc *ItemService) CsvImport(ctx context.Context, GID uuid.UUID, data io.Re
 
 		item, err = svc.repo.Items.UpdateByGroup(ctx, GID, updateItem)
 		if err != nil {
-			return 0, err
+			return report, err
 		}
 
-		finished++
-	}
+		if err := svc.repo.ItemImportHashes.Set(ctx, GID, item.ID, rowHash); err != nil {
+			return report, fmt.Errorf("failed to store import hash for item %q: %w", item.ID, err)
+		}
 
-	// Ooi J Sen
-	// Display error messages in console
-	if errorMessage != "" {
-		// Log or handle the error message here
-		fmt.Println("Error Messages:")
-		fmt.Println(errorMessage)
-		return 0, fmt.Errorf("Errors detected in CSV:\n%s", errorMessage)
+		if createRequired {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+
+		if progress != nil {
+			status := RowStatusUpdated
+			if createRequired {
+				status = RowStatusCreated
+			}
+			progress <- ImportProgress{Row: i, Status: status}
+		}
 	}
 
-	return finished, nil
+	return report, nil
 }
 
 func (svc *ItemService) ExportTSV(ctx context.Context, GID uuid.UUID) ([][]string, error) {
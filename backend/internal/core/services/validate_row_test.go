@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImportSchema_ValidateRow(t *testing.T) {
+	schema := DefaultImportSchema()
+
+	t.Run("valid row produces no errors", func(t *testing.T) {
+		row := ImportRow{Quantity: 1, PurchasePrice: 10}
+		if errs := schema.ValidateRow(0, &row); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %+v", errs)
+		}
+	})
+
+	t.Run("negative quantity fails non_negative and reports a 1-indexed row", func(t *testing.T) {
+		row := ImportRow{Quantity: -1}
+		errs := schema.ValidateRow(4, &row)
+		if len(errs) != 1 {
+			t.Fatalf("expected exactly one error, got %+v", errs)
+		}
+		if errs[0].Row != 5 {
+			t.Fatalf("expected row index 4 to report Row 5, got %d", errs[0].Row)
+		}
+		if errs[0].Rule != "non_negative" {
+			t.Fatalf("expected the non_negative rule, got %q", errs[0].Rule)
+		}
+	})
+
+	t.Run("sold_time before purchase_time fails date_before", func(t *testing.T) {
+		row := ImportRow{
+			PurchaseTime: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			SoldTime:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		errs := schema.ValidateRow(0, &row)
+
+		var found bool
+		for _, err := range errs {
+			if err.Rule == "date_before" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected a date_before error, got %+v", errs)
+		}
+	})
+}
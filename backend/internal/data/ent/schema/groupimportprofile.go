@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+	"github.com/google/uuid"
+)
+
+// GroupImportProfile stores a group's optional custom ImportSchema profile (see
+// services.LoadImportSchema), uploaded once and reused by every CsvImport for that group
+// instead of the built-in DefaultImportSchema. One row per group.
+type GroupImportProfile struct {
+	ent.Schema
+}
+
+func (GroupImportProfile) Mixin() []ent.Mixin {
+	return []ent.Mixin{
+		mixin.Time{},
+	}
+}
+
+func (GroupImportProfile) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", uuid.UUID{}).
+			Default(uuid.New).
+			Unique().
+			Immutable(),
+		field.UUID("group_id", uuid.UUID{}).
+			Unique().
+			Comment("the group this profile belongs to; one row per group"),
+		field.Bytes("data").
+			Comment("the profile file as uploaded, parsed by services.LoadImportSchema"),
+		field.String("format").
+			Comment(`"json", "yaml" or "yml"; see services.LoadImportSchema`),
+	}
+}
+
+func (GroupImportProfile) Edges() []ent.Edge {
+	return nil
+}
+
+func (GroupImportProfile) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("group_id").Unique(),
+	}
+}
@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+	"github.com/google/uuid"
+
+	"github.com/hay-kot/homebox/backend/internal/data/ent/schema/mixins"
+)
+
+// ItemImportHash tracks the sha256 fingerprint CsvImport last wrote for an item, so a later
+// re-import of an unchanged row can be recognized as a no-op. It is its own table rather than
+// a field on Item so this feature doesn't require a migration of Item itself: one row per
+// item, created the first time CsvImport writes to it and overwritten on every import after.
+type ItemImportHash struct {
+	ent.Schema
+}
+
+func (ItemImportHash) Mixin() []ent.Mixin {
+	return []ent.Mixin{
+		mixins.ImportHashMixin{},
+		mixin.Time{},
+	}
+}
+
+func (ItemImportHash) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", uuid.UUID{}).
+			Default(uuid.New).
+			Unique().
+			Immutable(),
+		field.UUID("item_id", uuid.UUID{}).
+			Unique().
+			Comment("the item this hash belongs to; one row per item"),
+		field.UUID("group_id", uuid.UUID{}).
+			Comment("denormalized from the item so lookups don't need a join"),
+	}
+}
+
+func (ItemImportHash) Edges() []ent.Edge {
+	return nil
+}
+
+func (ItemImportHash) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("item_id").Unique(),
+		index.Fields("group_id"),
+	}
+}
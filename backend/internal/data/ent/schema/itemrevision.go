@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+	"github.com/google/uuid"
+
+	"github.com/hay-kot/homebox/backend/internal/data/ent/schema/mixins"
+)
+
+// ItemRevision is a point-in-time snapshot of an item's importable fields, written to the
+// item_revisions table by a ConflictCreateRevision import just before the item's current
+// values are overwritten, so they aren't lost. It holds the scalar fields below; the item's
+// location, labels, asset ID and custom fields are not part of it.
+type ItemRevision struct {
+	ent.Schema
+}
+
+func (ItemRevision) Mixin() []ent.Mixin {
+	return []ent.Mixin{
+		mixins.ImportHashMixin{},
+		mixin.Time{},
+	}
+}
+
+func (ItemRevision) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", uuid.UUID{}).
+			Default(uuid.New).
+			Unique().
+			Immutable(),
+		field.UUID("item_id", uuid.UUID{}).
+			Comment("the item this revision is a snapshot of"),
+		field.UUID("group_id", uuid.UUID{}).
+			Comment("denormalized from the item so revisions can be queried without a join"),
+
+		field.String("name"),
+		field.String("description").Optional(),
+		field.Int("quantity").Default(1),
+		field.Bool("insured").Default(false),
+		field.Bool("archived").Default(false),
+		field.String("notes").Optional(),
+
+		field.Float("purchase_price").Default(0),
+		field.String("purchase_from").Optional(),
+		field.Time("purchase_time").Optional(),
+
+		field.String("manufacturer").Optional(),
+		field.String("model_number").Optional(),
+		field.String("serial_number").Optional(),
+
+		field.Bool("lifetime_warranty").Default(false),
+		field.Time("warranty_expires").Optional(),
+		field.String("warranty_details").Optional(),
+
+		field.String("sold_to").Optional(),
+		field.Time("sold_time").Optional(),
+		field.Float("sold_price").Default(0),
+		field.String("sold_notes").Optional(),
+	}
+}
+
+func (ItemRevision) Edges() []ent.Edge {
+	return nil
+}
+
+func (ItemRevision) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("item_id"),
+		index.Fields("group_id"),
+	}
+}
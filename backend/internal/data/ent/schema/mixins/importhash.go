@@ -0,0 +1,26 @@
+package mixins
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/mixin"
+)
+
+// ImportHashMixin adds the ImportHash field CsvImport uses to recognize a re-imported row
+// whose data hasn't changed since the last import, so it can be treated as a no-op instead
+// of being written again. It is embedded by ItemImportHash (the current hash for each item,
+// kept off Item itself so this feature doesn't require a migration of that table) and by
+// ItemRevision (so a stored revision records which import hash produced it).
+type ImportHashMixin struct {
+	mixin.Schema
+}
+
+var _ ent.Mixin = ImportHashMixin{}
+
+func (ImportHashMixin) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("import_hash").
+			Optional().
+			Comment("sha256 fingerprint of the row that last wrote this item, set by CsvImport"),
+	}
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/hay-kot/homebox/backend/internal/data/ent"
+	"github.com/hay-kot/homebox/backend/internal/data/ent/groupimportprofile"
+)
+
+// GroupImportProfilesRepository persists each group's optional custom import schema profile
+// (see ent/schema/groupimportprofile.go), the backing storage services.groupImportSchema
+// reads from instead of always falling back to DefaultImportSchema.
+type GroupImportProfilesRepository struct {
+	db *ent.Client
+}
+
+func NewGroupImportProfilesRepository(db *ent.Client) *GroupImportProfilesRepository {
+	return &GroupImportProfilesRepository{db: db}
+}
+
+// Get returns GID's stored import profile, or a nil data slice if the group has never
+// uploaded one.
+func (r *GroupImportProfilesRepository) Get(ctx context.Context, GID uuid.UUID) (data []byte, format string, err error) {
+	profile, err := r.db.GroupImportProfile.Query().
+		Where(groupimportprofile.GroupID(GID)).
+		Only(ctx)
+	switch {
+	case ent.IsNotFound(err):
+		return nil, "", nil
+	case err != nil:
+		return nil, "", err
+	}
+	return profile.Data, profile.Format, nil
+}
+
+// Set replaces GID's stored import profile, creating it on the first upload.
+func (r *GroupImportProfilesRepository) Set(ctx context.Context, GID uuid.UUID, data []byte, format string) error {
+	return r.db.GroupImportProfile.Create().
+		SetGroupID(GID).
+		SetData(data).
+		SetFormat(format).
+		OnConflictColumns(groupimportprofile.FieldGroupID).
+		UpdateData().
+		UpdateFormat().
+		Exec(ctx)
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/hay-kot/homebox/backend/internal/data/ent"
+	"github.com/hay-kot/homebox/backend/internal/data/ent/itemimporthash"
+)
+
+// ItemImportHashesRepository persists the current import fingerprint for each item (see
+// ent/schema/itemimporthash.go), letting CsvImport recognize an unchanged re-imported row
+// without a field on Item itself.
+type ItemImportHashesRepository struct {
+	db *ent.Client
+}
+
+func NewItemImportHashesRepository(db *ent.Client) *ItemImportHashesRepository {
+	return &ItemImportHashesRepository{db: db}
+}
+
+// Get returns itemID's currently stored import hash, and ok=false if CsvImport has never
+// written to this item before.
+func (r *ItemImportHashesRepository) Get(ctx context.Context, itemID uuid.UUID) (hash string, ok bool, err error) {
+	row, err := r.db.ItemImportHash.Query().
+		Where(itemimporthash.ItemID(itemID)).
+		Only(ctx)
+	switch {
+	case ent.IsNotFound(err):
+		return "", false, nil
+	case err != nil:
+		return "", false, err
+	}
+	return row.ImportHash, true, nil
+}
+
+// Set overwrites itemID's stored import hash, creating the row on the item's first import.
+func (r *ItemImportHashesRepository) Set(ctx context.Context, GID, itemID uuid.UUID, hash string) error {
+	return r.db.ItemImportHash.Create().
+		SetItemID(itemID).
+		SetGroupID(GID).
+		SetImportHash(hash).
+		OnConflictColumns(itemimporthash.FieldItemID).
+		UpdateImportHash().
+		Exec(ctx)
+}
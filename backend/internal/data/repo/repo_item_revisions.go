@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/hay-kot/homebox/backend/internal/data/ent"
+)
+
+// ItemRevisionsRepository persists snapshots to the item_revisions table (see
+// ent/schema/itemrevision.go), written by a ConflictCreateRevision import just before it
+// overwrites an item's current values.
+type ItemRevisionsRepository struct {
+	db *ent.Client
+}
+
+func NewItemRevisionsRepository(db *ent.Client) *ItemRevisionsRepository {
+	return &ItemRevisionsRepository{db: db}
+}
+
+// Create snapshots item's current importable field values into item_revisions. importHash is
+// the hash item was stored under before this revision (from ItemImportHashesRepository.Get),
+// passed in explicitly rather than read off item, since ImportHash isn't a field on Item.
+func (r *ItemRevisionsRepository) Create(ctx context.Context, GID uuid.UUID, item ItemOut, importHash string) error {
+	_, err := r.db.ItemRevision.Create().
+		SetItemID(item.ID).
+		SetGroupID(GID).
+		SetName(item.Name).
+		SetDescription(item.Description).
+		SetQuantity(item.Quantity).
+		SetInsured(item.Insured).
+		SetArchived(item.Archived).
+		SetNotes(item.Notes).
+		SetPurchasePrice(item.PurchasePrice).
+		SetPurchaseFrom(item.PurchaseFrom).
+		SetPurchaseTime(item.PurchaseTime).
+		SetManufacturer(item.Manufacturer).
+		SetModelNumber(item.ModelNumber).
+		SetSerialNumber(item.SerialNumber).
+		SetLifetimeWarranty(item.LifetimeWarranty).
+		SetWarrantyExpires(item.WarrantyExpires).
+		SetWarrantyDetails(item.WarrantyDetails).
+		SetSoldTo(item.SoldTo).
+		SetSoldTime(item.SoldTime).
+		SetSoldPrice(item.SoldPrice).
+		SetSoldNotes(item.SoldNotes).
+		SetImportHash(importHash).
+		Save(ctx)
+	return err
+}